@@ -0,0 +1,407 @@
+package dynparquet
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/parquet-go"
+)
+
+// structTagKey is the struct tag used to customize how a field maps onto a
+// dynparquet column, e.g. `frostdb:"labels,dyn"`.
+const structTagKey = "frostdb"
+
+// fieldPlan describes how a single (possibly nested) struct field maps onto
+// parquet columns.
+type fieldPlan struct {
+	name     string
+	index    []int // reflect.Value.FieldByIndex path
+	kind     reflect.Kind
+	elemKind reflect.Kind // for slices/maps, the kind of the contained value
+	dynamic  bool         // true for map[string]string fields tagged ",dyn"
+	repeated bool         // true for slice fields
+	isTime   bool         // true for time.Time fields, encoded as INT64 TIMESTAMP
+}
+
+// StructPlan is a compiled mapping from a Go struct type to a dynparquet
+// Schema and the column each field encodes to. Building a StructPlan walks
+// the struct via reflection once; encoding a row with an existing plan does
+// not need to reflect on the type again.
+type StructPlan struct {
+	typ    reflect.Type
+	schema *Schema
+	fields []fieldPlan
+}
+
+var planCache sync.Map // reflect.Type -> *StructPlan
+
+// PlanForStruct returns the cached StructPlan for t, building and caching
+// one if this is the first time t is seen. t must be a struct type (not a
+// pointer to one).
+func PlanForStruct(t reflect.Type) (*StructPlan, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dynparquet: %s is not a struct", t)
+	}
+
+	if cached, ok := planCache.Load(t); ok {
+		return cached.(*StructPlan), nil
+	}
+
+	plan, err := newStructPlan(t)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := planCache.LoadOrStore(t, plan)
+	return actual.(*StructPlan), nil
+}
+
+// SchemaFromStruct returns the dynparquet.Schema that t encodes to, building
+// and caching the struct's plan if necessary.
+func SchemaFromStruct(t reflect.Type) (*Schema, error) {
+	plan, err := PlanForStruct(t)
+	if err != nil {
+		return nil, err
+	}
+	return plan.schema, nil
+}
+
+func newStructPlan(t reflect.Type) (*StructPlan, error) {
+	plan := &StructPlan{typ: t}
+
+	var columns []ColumnDefinition
+	var sortingColumns []SortingColumn
+
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue // unexported
+			}
+
+			fieldIndex := append(append([]int{}, index...), i)
+			fieldType := sf.Type
+			tag, opts := parseTag(sf)
+			if tag == "-" {
+				continue
+			}
+			name := sf.Name
+			if tag != "" {
+				name = tag
+			}
+
+			// Nested (non-time) structs are flattened into the parent's
+			// column set, mirroring how an embedded Go struct is addressed.
+			if fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+				if err := walk(fieldType, fieldIndex); err != nil {
+					return err
+				}
+				continue
+			}
+
+			fp := fieldPlan{name: name, index: fieldIndex, kind: fieldType.Kind()}
+
+			switch {
+			case fieldType == reflect.TypeOf(time.Time{}):
+				fp.isTime = true
+				columns = append(columns, ColumnDefinition{
+					Name:          name,
+					StorageLayout: parquet.Int(64),
+					Dynamic:       false,
+				})
+			case opts.has("dyn"):
+				if fieldType.Kind() != reflect.Map || fieldType.Key().Kind() != reflect.String {
+					return fmt.Errorf("dynparquet: field %s tagged dyn must be a map[string]<T>", sf.Name)
+				}
+				fp.dynamic = true
+				fp.elemKind = fieldType.Elem().Kind()
+				columns = append(columns, ColumnDefinition{
+					Name:          name,
+					StorageLayout: storageLayoutFor(fieldType.Elem()),
+					Dynamic:       true,
+				})
+			case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() != reflect.Uint8:
+				fp.repeated = true
+				fp.elemKind = fieldType.Elem().Kind()
+				columns = append(columns, ColumnDefinition{
+					Name:          name,
+					StorageLayout: storageLayoutFor(fieldType.Elem()),
+					Dynamic:       false,
+				})
+			default:
+				underlying := fieldType
+				if underlying.Kind() == reflect.Ptr {
+					underlying = underlying.Elem()
+				}
+				columns = append(columns, ColumnDefinition{
+					Name:          name,
+					StorageLayout: storageLayoutFor(underlying),
+					Dynamic:       false,
+				})
+			}
+
+			if opts.has("sorting") {
+				sortingColumns = append(sortingColumns, Ascending(name))
+			}
+
+			plan.fields = append(plan.fields, fp)
+		}
+		return nil
+	}
+
+	if err := walk(t, nil); err != nil {
+		return nil, err
+	}
+
+	schema, err := NewSchema(t.Name(), columns, sortingColumns)
+	if err != nil {
+		return nil, fmt.Errorf("dynparquet: building schema for %s: %w", t, err)
+	}
+	plan.schema = schema
+
+	return plan, nil
+}
+
+// storageLayoutFor picks the parquet node for a Go kind. Only the kinds
+// that show up in practice for metric/log/trace-shaped structs are
+// supported; any other kind gets a string column here, and encodeScalar
+// rejects a value of that kind when a row is encoded.
+func storageLayoutFor(t reflect.Type) parquet.Node {
+	switch t.Kind() {
+	case reflect.String:
+		return parquet.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return parquet.Int(32)
+	case reflect.Int64:
+		return parquet.Int(64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return parquet.Uint(32)
+	case reflect.Uint64:
+		return parquet.Uint(64)
+	case reflect.Float32:
+		return parquet.Leaf(parquet.FloatType)
+	case reflect.Float64:
+		return parquet.Leaf(parquet.DoubleType)
+	case reflect.Bool:
+		return parquet.Leaf(parquet.BooleanType)
+	default:
+		// Fall back to string so that SchemaFromStruct never panics; callers
+		// that need a specific encoding for an unusual type should add a
+		// case above rather than relying on this.
+		return parquet.String()
+	}
+}
+
+type tagOptions []string
+
+func (o tagOptions) has(opt string) bool {
+	for _, s := range o {
+		if s == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTag(sf reflect.StructField) (string, tagOptions) {
+	raw, ok := sf.Tag.Lookup(structTagKey)
+	if !ok {
+		return "", nil
+	}
+	parts := strings.Split(raw, ",")
+	return parts[0], tagOptions(parts[1:])
+}
+
+// Rows encodes values, a slice of structs of the type the plan was built
+// for, into a Buffer ready to be inserted into a Table. Reusing the same
+// plan across many calls avoids re-walking the struct's fields by
+// reflection on every insert, the cost SchemaFromStruct pays once.
+//
+// Dynamic (",dyn") columns only materialize the keys actually present in
+// values, so the set of keys per dynamic column is collected in a first
+// pass over the batch and used to size the buffer before any row is
+// encoded, the same way a hand-built dynparquet insert would.
+func (p *StructPlan) Rows(values reflect.Value) (*Buffer, error) {
+	if values.Kind() != reflect.Slice || values.Type().Elem() != p.typ {
+		return nil, fmt.Errorf("dynparquet: expected []%s, got %s", p.typ, values.Type())
+	}
+	n := values.Len()
+
+	dynamicColumns := p.collectDynamicColumns(values, n)
+
+	buf, err := p.schema.NewBuffer(dynamicColumns)
+	if err != nil {
+		return nil, fmt.Errorf("dynparquet: allocating buffer: %w", err)
+	}
+
+	row := make([]parquet.Value, 0, len(p.fields))
+	for i := 0; i < n; i++ {
+		row = row[:0]
+		v := values.Index(i)
+
+		for _, fp := range p.fields {
+			fv := v.FieldByIndex(fp.index)
+
+			var (
+				vals []parquet.Value
+				err  error
+			)
+			switch {
+			case fp.dynamic:
+				vals, err = fp.encodeDynamic(fv, dynamicColumns[fp.name])
+			case fp.repeated:
+				vals, err = fp.encodeRepeated(fv)
+			default:
+				var val parquet.Value
+				val, err = fp.encodeScalar(fv)
+				vals = []parquet.Value{val}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("dynparquet: encoding field %s: %w", fp.name, err)
+			}
+			row = append(row, vals...)
+		}
+
+		if _, err := buf.WriteRow(row); err != nil {
+			return nil, fmt.Errorf("dynparquet: writing row %d: %w", i, err)
+		}
+	}
+
+	return buf, nil
+}
+
+// collectDynamicColumns walks every dynamic field of every row in values
+// and returns, for each dynamic column name, the sorted union of map keys
+// present anywhere in the batch. This is the set NewBuffer needs to
+// materialize the per-key physical columns for this insert.
+func (p *StructPlan) collectDynamicColumns(values reflect.Value, n int) map[string][]string {
+	var dynamicFields []fieldPlan
+	for _, fp := range p.fields {
+		if fp.dynamic {
+			dynamicFields = append(dynamicFields, fp)
+		}
+	}
+	if len(dynamicFields) == 0 {
+		return nil
+	}
+
+	keySets := make(map[string]map[string]struct{}, len(dynamicFields))
+	for _, fp := range dynamicFields {
+		keySets[fp.name] = make(map[string]struct{})
+	}
+
+	for i := 0; i < n; i++ {
+		v := values.Index(i)
+		for _, fp := range dynamicFields {
+			m := v.FieldByIndex(fp.index)
+			if !m.IsValid() || m.IsNil() {
+				continue
+			}
+			for _, k := range m.MapKeys() {
+				keySets[fp.name][k.String()] = struct{}{}
+			}
+		}
+	}
+
+	dynamicColumns := make(map[string][]string, len(dynamicFields))
+	for name, set := range keySets {
+		keys := make([]string, 0, len(set))
+		for k := range set {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		dynamicColumns[name] = keys
+	}
+	return dynamicColumns
+}
+
+// encodeDynamic encodes a map[string]T field into one parquet.Value per
+// key in keys — the sorted union of keys present anywhere in the batch,
+// as decided by collectDynamicColumns — using a null for rows where this
+// particular row's map doesn't have that key.
+func (fp fieldPlan) encodeDynamic(v reflect.Value, keys []string) ([]parquet.Value, error) {
+	out := make([]parquet.Value, len(keys))
+	for i, k := range keys {
+		if v.IsValid() && !v.IsNil() {
+			mv := v.MapIndex(reflect.ValueOf(k))
+			if mv.IsValid() {
+				val, err := fp.encodeScalar(mv)
+				if err != nil {
+					return nil, err
+				}
+				out[i] = val
+				continue
+			}
+		}
+		out[i] = parquet.NullValue()
+	}
+	return out, nil
+}
+
+// encodeRepeated encodes a slice field into one parquet.Value per element,
+// marking every element after the first as a repetition of the same
+// column so the row carries all of them under a single repeated column.
+func (fp fieldPlan) encodeRepeated(v reflect.Value) ([]parquet.Value, error) {
+	if !v.IsValid() || v.IsNil() || v.Len() == 0 {
+		return []parquet.Value{parquet.NullValue()}, nil
+	}
+
+	out := make([]parquet.Value, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		val, err := fp.encodeScalar(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		repetitionLevel := 0
+		if i > 0 {
+			repetitionLevel = 1
+		}
+		out[i] = val.Level(repetitionLevel, 1, 0)
+	}
+	return out, nil
+}
+
+// encodeScalar encodes a single leaf value: a plain field, one element of a
+// repeated field, or one value of a dynamic column's map.
+func (fp fieldPlan) encodeScalar(v reflect.Value) (parquet.Value, error) {
+	if fp.isTime {
+		t, ok := v.Interface().(time.Time)
+		if !ok {
+			return parquet.Value{}, fmt.Errorf("expected time.Time, got %s", v.Type())
+		}
+		return parquet.Int64Value(t.UnixNano()), nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return parquet.NullValue(), nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return parquet.ByteArrayValue([]byte(v.String())), nil
+	case reflect.Int64:
+		return parquet.Int64Value(v.Int()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		// storageLayoutFor declares these as 32-bit columns; Int64Value here
+		// would write a value wider than the column's physical type.
+		return parquet.Int32Value(int32(v.Int())), nil
+	case reflect.Uint64:
+		return parquet.Int64Value(int64(v.Uint())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return parquet.Int32Value(int32(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return parquet.DoubleValue(v.Float()), nil
+	case reflect.Bool:
+		return parquet.BooleanValue(v.Bool()), nil
+	default:
+		return parquet.Value{}, fmt.Errorf("unsupported field kind %s", v.Kind())
+	}
+}
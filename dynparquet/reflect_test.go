@@ -0,0 +1,141 @@
+package dynparquet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sampleMetric struct {
+	Name      string            `frostdb:"name"`
+	Value     float64           `frostdb:"value"`
+	Count     int32             `frostdb:"count"`
+	Total     int64             `frostdb:"total"`
+	Errors    uint32            `frostdb:"errors"`
+	Sequence  uint64            `frostdb:"sequence"`
+	Labels    map[string]string `frostdb:"labels,dyn"`
+	Timestamp time.Time         `frostdb:"timestamp"`
+}
+
+func fieldPlanFor(t *testing.T, plan *StructPlan, name string) fieldPlan {
+	t.Helper()
+	for _, fp := range plan.fields {
+		if fp.name == name {
+			return fp
+		}
+	}
+	t.Fatalf("no field plan named %q", name)
+	return fieldPlan{}
+}
+
+func TestPlanForStructCaches(t *testing.T) {
+	typ := reflect.TypeOf(sampleMetric{})
+
+	p1, err := PlanForStruct(typ)
+	require.NoError(t, err)
+
+	p2, err := PlanForStruct(typ)
+	require.NoError(t, err)
+
+	require.Same(t, p1, p2, "PlanForStruct should reuse the cached plan for the same type")
+}
+
+func TestStructPlanRowsEncodesBatch(t *testing.T) {
+	typ := reflect.TypeOf(sampleMetric{})
+	plan, err := PlanForStruct(typ)
+	require.NoError(t, err)
+
+	rows := []sampleMetric{
+		{
+			Name:      "cpu",
+			Value:     0.42,
+			Count:     -7,
+			Total:     1 << 40,
+			Errors:    3,
+			Sequence:  1 << 40,
+			Labels:    map[string]string{"host": "a"},
+			Timestamp: time.Unix(0, 1),
+		},
+		{
+			Name:      "mem",
+			Value:     1.5,
+			Count:     9,
+			Total:     -(1 << 40),
+			Errors:    4,
+			Sequence:  2,
+			Labels:    map[string]string{"host": "b"},
+			Timestamp: time.Unix(0, 2),
+		},
+	}
+
+	buf, err := plan.Rows(reflect.ValueOf(rows))
+	require.NoError(t, err)
+	require.NotNil(t, buf)
+}
+
+// TestFieldPlanEncodeScalarWidth guards against encodeScalar writing a
+// value wider than the column storageLayoutFor declared for it: a 32-bit
+// int/uint field must produce an Int32 parquet.Value, matching the 32-bit
+// INT32/UINT32 column storageLayoutFor emits for those kinds, not the
+// Int64Value a naive "every integer is an int64" encoding would use.
+func TestFieldPlanEncodeScalarWidth(t *testing.T) {
+	typ := reflect.TypeOf(sampleMetric{})
+	plan, err := PlanForStruct(typ)
+	require.NoError(t, err)
+
+	row := sampleMetric{Count: -7, Total: 1 << 40, Errors: 3, Sequence: 1 << 40}
+	v := reflect.ValueOf(row)
+
+	count := fieldPlanFor(t, plan, "count")
+	val, err := count.encodeScalar(v.FieldByIndex(count.index))
+	require.NoError(t, err)
+	require.Equal(t, int32(-7), val.Int32())
+
+	total := fieldPlanFor(t, plan, "total")
+	val, err = total.encodeScalar(v.FieldByIndex(total.index))
+	require.NoError(t, err)
+	require.Equal(t, int64(1<<40), val.Int64())
+
+	errors := fieldPlanFor(t, plan, "errors")
+	val, err = errors.encodeScalar(v.FieldByIndex(errors.index))
+	require.NoError(t, err)
+	require.Equal(t, int32(3), val.Int32())
+
+	sequence := fieldPlanFor(t, plan, "sequence")
+	val, err = sequence.encodeScalar(v.FieldByIndex(sequence.index))
+	require.NoError(t, err)
+	require.Equal(t, int64(1<<40), val.Int64())
+}
+
+func BenchmarkStructPlanRowsReused(b *testing.B) {
+	typ := reflect.TypeOf(sampleMetric{})
+	rows := []sampleMetric{{Name: "cpu", Value: 0.42, Labels: map[string]string{"host": "a"}, Timestamp: time.Now()}}
+
+	plan, err := PlanForStruct(typ)
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := plan.Rows(reflect.ValueOf(rows)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStructPlanRowsColdEveryTime(b *testing.B) {
+	typ := reflect.TypeOf(sampleMetric{})
+	rows := []sampleMetric{{Name: "cpu", Value: 0.42, Labels: map[string]string{"host": "a"}, Timestamp: time.Now()}}
+
+	for i := 0; i < b.N; i++ {
+		planCache.Delete(typ)
+		plan, err := PlanForStruct(typ)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := plan.Rows(reflect.ValueOf(rows)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/ipc"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/segmentio/parquet-go"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+// SerializeIPC writes the block's data to w using the Arrow IPC stream
+// format, as an alternative to the Parquet encoding produced by
+// Serialize. It is intended for consumers such as DuckDB or pyarrow that
+// read Arrow IPC natively. Persist does not call this; callers that want a
+// block's "data.arrow" written alongside its "data.parquet" must call
+// PersistIPC themselves after Persist succeeds.
+func (t *TableBlock) SerializeIPC(w io.Writer) (err error) {
+	var buf bytes.Buffer
+	if err := t.Serialize(&buf); err != nil {
+		return fmt.Errorf("failed to serialize block: %w", err)
+	}
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return fmt.Errorf("failed to open serialized block: %w", err)
+	}
+
+	serBuf, err := dynparquet.NewSerializedBuffer(file)
+	if err != nil {
+		return err
+	}
+
+	pool := memory.NewGoAllocator()
+
+	var ipcWriter *ipc.Writer
+	defer func() {
+		if ipcWriter == nil {
+			return
+		}
+		// Close flushes the final footer/EOS marker; a failure here means
+		// the data.arrow we just wrote is truncated or invalid, so it must
+		// not be swallowed the way a bare ipcWriter.Close() would.
+		if closeErr := ipcWriter.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close ipc writer: %w", closeErr)
+		}
+	}()
+
+	for i := 0; i < serBuf.NumRowGroups(); i++ {
+		rg := serBuf.DynamicRowGroup(i)
+
+		record, err := dynparquet.RowGroupToArrowRecord(pool, rg)
+		if err != nil {
+			return fmt.Errorf("failed to convert row group to arrow record: %w", err)
+		}
+
+		if ipcWriter == nil {
+			ipcWriter = ipc.NewWriter(w, ipc.WithSchema(record.Schema()))
+		}
+		err = ipcWriter.Write(record)
+		record.Release()
+		if err != nil {
+			return fmt.Errorf("failed to write ipc batch: %w", err)
+		}
+	}
+	return nil
+}
+
+// PersistIPC uploads the block's Arrow IPC encoding ("data.arrow") to the
+// underlying bucket, alongside the Parquet file written by Persist.
+func (t *TableBlock) PersistIPC() error {
+	if t.table.db.bucket == nil {
+		return nil
+	}
+
+	r, w := io.Pipe()
+	var err error
+	go func() {
+		defer w.Close()
+		err = t.SerializeIPC(w)
+	}()
+	defer r.Close()
+
+	fileName := filepath.Join(t.table.name, t.ulid.String(), "data.arrow")
+	if uploadErr := t.table.db.bucket.Upload(context.Background(), fileName, r); uploadErr != nil {
+		return fmt.Errorf("failed to upload ipc block: %v", uploadErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to serialize ipc block: %v", err)
+	}
+	return nil
+}
+
+// IterateBucketBlocksIPC mirrors IterateBucketBlocks, but reads each block's
+// "data.arrow" sibling with arrow/ipc and streams the record batches
+// straight into callback, without going through parquet-go at all. Blocks
+// persisted before Arrow IPC export was added (no "data.arrow" file) are
+// skipped.
+func (t *Table) IterateBucketBlocksIPC(ctx context.Context, logger log.Logger, callback func(ctx context.Context, r arrow.Record) error, lastBlockTimestamp uint64) error {
+	ctx, span := t.tracer.Start(ctx, "Table/IterateBucketBlocksIPC")
+	defer span.End()
+
+	if t.db.bucket == nil || t.db.ignoreStorageOnQuery {
+		return nil
+	}
+
+	n := 0
+	err := t.db.bucket.Iter(ctx, t.name, func(blockDir string) error {
+		ctx, span := t.tracer.Start(ctx, "Table/IterateBucketBlocksIPC/Iter")
+		defer span.End()
+
+		blockUlid, err := ulid.Parse(filepath.Base(blockDir))
+		if err != nil {
+			// Not a block directory (e.g. the table's index manifest).
+			return nil
+		}
+		if lastBlockTimestamp != 0 && blockUlid.Time() >= lastBlockTimestamp {
+			return nil
+		}
+
+		fileName := filepath.Join(blockDir, "data.arrow")
+		rc, err := t.db.bucket.Get(ctx, fileName)
+		if err != nil {
+			if t.db.bucket.IsObjNotFoundErr(err) {
+				return nil
+			}
+			return err
+		}
+		defer rc.Close()
+
+		reader, err := ipc.NewReader(rc, ipc.WithAllocator(memory.NewGoAllocator()))
+		if err != nil {
+			return fmt.Errorf("failed to open ipc reader: %w", err)
+		}
+		defer reader.Release()
+
+		n++
+		for reader.Next() {
+			rec := reader.Record()
+			rec.Retain()
+			err := callback(ctx, rec)
+			rec.Release()
+			if err != nil {
+				return err
+			}
+		}
+		return reader.Err()
+	})
+	level.Debug(logger).Log("msg", "read ipc blocks", "n", n)
+	return err
+}
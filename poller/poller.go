@@ -0,0 +1,417 @@
+// Package poller implements a background subsystem that keeps an in-memory
+// index of the blocks a DB has in object storage, so hot-path queries can
+// avoid repeated bucket.Iter/Attributes round-trips.
+package poller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thanos-io/objstore"
+)
+
+// indexFileName is the name of the per-table manifest written alongside
+// blocks so that cold starts can load the index with a single GET instead
+// of a full bucket LIST.
+const indexFileName = "index.json"
+
+// BlockMeta describes a single block as tracked by the poller's in-memory
+// index.
+type BlockMeta struct {
+	ULID    ulid.ULID `json:"ulid"`
+	Size    int64     `json:"size"`
+	MinTime int64     `json:"minTime"`
+	MaxTime int64     `json:"maxTime"`
+	Tenant  string    `json:"tenant"`
+	Table   string    `json:"table"`
+}
+
+// TimeRange is an inclusive [Start, End] time filter, in the same unit as
+// BlockMeta.MinTime/MaxTime. The zero value is unbounded.
+type TimeRange struct {
+	Start int64
+	End   int64
+}
+
+// Intersects reports whether the block's [MinTime, MaxTime] range overlaps r.
+//
+// A block whose MinTime and MaxTime are both zero is treated as having an
+// unknown range (blocks discovered via a bare bucket listing, rather than a
+// manifest carrying real statistics, have no range information) and always
+// matches: we have no basis to prove it doesn't contain relevant data, and
+// filtering it out would silently drop real blocks from every time-bounded
+// query.
+func (b BlockMeta) Intersects(r TimeRange) bool {
+	if r.Start == 0 && r.End == 0 {
+		return true
+	}
+	if b.MinTime == 0 && b.MaxTime == 0 {
+		return true
+	}
+	if r.End != 0 && b.MinTime > r.End {
+		return false
+	}
+	if r.Start != 0 && b.MaxTime < r.Start {
+		return false
+	}
+	return true
+}
+
+// Config configures a BlockPoller.
+type Config struct {
+	// Tenant identifies the owner of the bucket being polled. It is only
+	// used to label BlockMeta entries and metrics; it does not affect how
+	// the bucket is listed.
+	Tenant string
+	// PollInterval is how often the bucket is re-listed.
+	PollInterval time.Duration
+	// MaxConsecutiveErrors is the number of consecutive poll failures
+	// tolerated before the poller logs at error level. It never stops
+	// polling on its own.
+	MaxConsecutiveErrors int
+}
+
+func (c Config) withDefaults() Config {
+	if c.PollInterval <= 0 {
+		c.PollInterval = 5 * time.Minute
+	}
+	if c.MaxConsecutiveErrors <= 0 {
+		c.MaxConsecutiveErrors = 3
+	}
+	return c
+}
+
+// Metrics holds the prometheus instrumentation emitted by a BlockPoller.
+type Metrics struct {
+	listDuration     prometheus.Histogram
+	blocksDiscovered prometheus.Counter
+	blocksTombstoned prometheus.Counter
+	cacheHits        prometheus.Counter
+	cacheMisses      prometheus.Counter
+}
+
+// NewMetrics registers and returns the poller's metrics on reg. reg may be
+// nil, in which case the metrics are created but not registered.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		listDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "frostdb_poller_list_duration_seconds",
+			Help:    "Duration of bucket list operations performed by the block poller.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		blocksDiscovered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frostdb_poller_blocks_discovered_total",
+			Help: "Number of blocks discovered by the block poller.",
+		}),
+		blocksTombstoned: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frostdb_poller_blocks_tombstoned_total",
+			Help: "Number of blocks removed from the index because they no longer exist in the bucket.",
+		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frostdb_poller_cache_hits_total",
+			Help: "Number of block lookups served from the poller's in-memory index.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "frostdb_poller_cache_misses_total",
+			Help: "Number of block lookups for a table the poller has not indexed yet.",
+		}),
+	}
+	if reg != nil {
+		reg.MustRegister(
+			m.listDuration,
+			m.blocksDiscovered,
+			m.blocksTombstoned,
+			m.cacheHits,
+			m.cacheMisses,
+		)
+	}
+	return m
+}
+
+// BlockPoller periodically lists a DB's bucket and keeps an in-memory index
+// of the blocks found per table, so that callers such as
+// Table.IterateBucketBlocks don't need to hit the bucket on every query.
+type BlockPoller struct {
+	logger  log.Logger
+	bucket  objstore.Bucket
+	config  Config
+	metrics *Metrics
+
+	mtx   sync.RWMutex
+	index map[string][]BlockMeta // table -> blocks
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a BlockPoller that will list blocks out of bucket once Run is
+// called.
+func New(logger log.Logger, bucket objstore.Bucket, config Config, metrics *Metrics) *BlockPoller {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	if metrics == nil {
+		metrics = NewMetrics(nil)
+	}
+	return &BlockPoller{
+		logger:  logger,
+		bucket:  bucket,
+		config:  config.withDefaults(),
+		metrics: metrics,
+		index:   make(map[string][]BlockMeta),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run starts the polling loop and blocks until ctx is canceled or Shutdown
+// is called. Callers are expected to run it in its own goroutine, e.g.
+// `go blockPoller.Run(ctx)`.
+func (p *BlockPoller) Run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	defer close(p.done)
+
+	consecutiveErrors := 0
+	poll := func() {
+		if err := p.pollOnce(ctx); err != nil {
+			consecutiveErrors++
+			if consecutiveErrors >= p.config.MaxConsecutiveErrors {
+				level.Error(p.logger).Log("msg", "block poller failed repeatedly", "consecutiveErrors", consecutiveErrors, "err", err)
+			} else {
+				level.Debug(p.logger).Log("msg", "block poller failed", "err", err)
+			}
+			return
+		}
+		consecutiveErrors = 0
+	}
+
+	poll()
+
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			poll()
+		}
+	}
+}
+
+// Shutdown stops the polling goroutine and waits for it to exit.
+func (p *BlockPoller) Shutdown() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// Blocks returns the cached blocks for table that intersect r, and whether
+// the table has been indexed at all (a cache miss should fall back to a
+// bucket listing rather than being treated as "no blocks"). The returned
+// slice is a copy and safe to retain.
+func (p *BlockPoller) Blocks(table string, r TimeRange) (blocks []BlockMeta, ok bool) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	cached, ok := p.index[table]
+	if !ok {
+		p.metrics.cacheMisses.Inc()
+		return nil, false
+	}
+
+	p.metrics.cacheHits.Inc()
+	out := make([]BlockMeta, 0, len(cached))
+	for _, b := range cached {
+		if b.Intersects(r) {
+			out = append(out, b)
+		}
+	}
+	return out, true
+}
+
+// pollOnce lists the bucket's top-level table directories and rebuilds the
+// index for each.
+func (p *BlockPoller) pollOnce(ctx context.Context) error {
+	start := time.Now()
+	defer func() { p.metrics.listDuration.Observe(time.Since(start).Seconds()) }()
+
+	newIndex := make(map[string][]BlockMeta)
+
+	err := p.bucket.Iter(ctx, "", func(tableDir string) error {
+		table := filepath.Base(filepath.Clean(tableDir))
+		blocks, err := p.listTable(ctx, table)
+		if err != nil {
+			return err
+		}
+		newIndex[table] = blocks
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("poll bucket: %w", err)
+	}
+
+	p.mtx.Lock()
+	// A table whose last block was removed no longer shows up in the
+	// listing; keep it indexed with no blocks so lookups report it as known
+	// and empty rather than as a cache miss.
+	for table := range p.index {
+		if _, ok := newIndex[table]; !ok {
+			newIndex[table] = nil
+		}
+	}
+	tombstoned := p.countMissing(newIndex)
+	prev := p.index
+	p.index = newIndex
+	// p.index is now the new listing, so this counts the blocks that were
+	// absent from the previous one.
+	discovered := p.countMissing(prev)
+	p.mtx.Unlock()
+
+	p.metrics.blocksDiscovered.Add(float64(discovered))
+	p.metrics.blocksTombstoned.Add(float64(tombstoned))
+	return nil
+}
+
+// countMissing returns how many blocks present in the current index are
+// absent from next. Must be called with p.mtx held.
+func (p *BlockPoller) countMissing(next map[string][]BlockMeta) int {
+	seen := func(table string, id ulid.ULID) bool {
+		for _, b := range next[table] {
+			if b.ULID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	missing := 0
+	for table, blocks := range p.index {
+		for _, b := range blocks {
+			if !seen(table, b.ULID) {
+				missing++
+			}
+		}
+	}
+	return missing
+}
+
+// listTable first attempts to load the table's index manifest written by
+// the compactor/persister (a single GET); if it is missing or unreadable it
+// falls back to a full bucket.Iter over the table's blocks.
+func (p *BlockPoller) listTable(ctx context.Context, table string) ([]BlockMeta, error) {
+	if blocks, err := p.readTableIndex(ctx, table); err == nil {
+		return blocks, nil
+	}
+
+	var blocks []BlockMeta
+	err := p.bucket.Iter(ctx, table, func(blockDir string) error {
+		id, err := ulid.Parse(filepath.Base(blockDir))
+		if err != nil {
+			// Not a block directory (e.g. the index manifest itself).
+			return nil
+		}
+
+		blockName := filepath.Join(blockDir, "data.parquet")
+		attribs, err := p.bucket.Attributes(ctx, blockName)
+		if err != nil {
+			return err
+		}
+
+		blocks = append(blocks, BlockMeta{
+			ULID:   id,
+			Size:   attribs.Size,
+			Tenant: p.config.Tenant,
+			Table:  table,
+			// MinTime/MaxTime require opening the parquet footer, which is
+			// exactly what the cache exists to avoid; they are populated
+			// from the manifest written by WriteTableIndex, whose caller
+			// already has the row group statistics on hand.
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (p *BlockPoller) readTableIndex(ctx context.Context, table string) ([]BlockMeta, error) {
+	return ReadTableIndex(ctx, p.bucket, table)
+}
+
+// ReadTableIndex loads the table's manifest of blocks, as written by
+// WriteTableIndex/AppendBlockToIndex. Callers should treat
+// bucket.IsObjNotFoundErr(err) as "no manifest yet" rather than a hard
+// failure.
+func ReadTableIndex(ctx context.Context, bucket objstore.Bucket, table string) ([]BlockMeta, error) {
+	name := filepath.Join(table, indexFileName)
+	rc, err := bucket.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var blocks []BlockMeta
+	if err := json.NewDecoder(rc).Decode(&blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// WriteTableIndex uploads the table's manifest listing blocks, so that a
+// cold BlockPoller can load it with a single GET instead of a full LIST. It
+// overwrites whatever manifest already exists; callers that only know
+// about a single newly-persisted block should use AppendBlockToIndex
+// instead.
+func WriteTableIndex(ctx context.Context, bucket objstore.Bucket, table string, blocks []BlockMeta) error {
+	buf, err := json.Marshal(blocks)
+	if err != nil {
+		return fmt.Errorf("marshal table index: %w", err)
+	}
+
+	name := filepath.Join(table, indexFileName)
+	if err := bucket.Upload(ctx, name, bytes.NewReader(buf)); err != nil {
+		return fmt.Errorf("upload table index: %w", err)
+	}
+	return nil
+}
+
+// AppendBlockToIndex merges meta into the table's existing index manifest
+// (replacing any prior entry with the same ULID) and re-uploads it. It is
+// meant to be called by TableBlock.Persist/the compactor immediately after
+// a block finishes uploading, so the manifest always reflects every
+// persisted block rather than just the most recent one.
+func AppendBlockToIndex(ctx context.Context, bucket objstore.Bucket, table string, meta BlockMeta) error {
+	existing, err := ReadTableIndex(ctx, bucket, table)
+	if err != nil && !bucket.IsObjNotFoundErr(err) {
+		return fmt.Errorf("read existing table index: %w", err)
+	}
+
+	merged := make([]BlockMeta, 0, len(existing)+1)
+	replaced := false
+	for _, b := range existing {
+		if b.ULID == meta.ULID {
+			merged = append(merged, meta)
+			replaced = true
+			continue
+		}
+		merged = append(merged, b)
+	}
+	if !replaced {
+		merged = append(merged, meta)
+	}
+
+	return WriteTableIndex(ctx, bucket, table, merged)
+}
@@ -0,0 +1,206 @@
+package poller
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+// stubBucket is a minimal in-memory objstore.Bucket that counts Iter and
+// Attributes calls, so tests can assert the poller's cache avoids them.
+type stubBucket struct {
+	objstore.Bucket
+
+	objects map[string][]byte
+
+	iterCalls       int64
+	attributesCalls int64
+}
+
+func newStubBucket() *stubBucket {
+	return &stubBucket{objects: make(map[string][]byte)}
+}
+
+func (b *stubBucket) Name() string { return "stub" }
+
+func (b *stubBucket) Upload(_ context.Context, name string, r io.Reader) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	b.objects[name] = buf
+	return nil
+}
+
+func (b *stubBucket) Delete(_ context.Context, name string) error {
+	delete(b.objects, name)
+	return nil
+}
+
+func (b *stubBucket) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	buf, ok := b.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: not found", name)
+	}
+	return io.NopCloser(bytes.NewReader(buf)), nil
+}
+
+func (b *stubBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	return b.Get(ctx, name)
+}
+
+func (b *stubBucket) Exists(_ context.Context, name string) (bool, error) {
+	_, ok := b.objects[name]
+	return ok, nil
+}
+
+func (b *stubBucket) IsObjNotFoundErr(err error) bool { return err != nil }
+
+func (b *stubBucket) IsAccessDeniedErr(error) bool { return false }
+
+func (b *stubBucket) Attributes(_ context.Context, name string) (objstore.ObjectAttributes, error) {
+	atomic.AddInt64(&b.attributesCalls, 1)
+	buf, ok := b.objects[name]
+	if !ok {
+		return objstore.ObjectAttributes{}, fmt.Errorf("%s: not found", name)
+	}
+	return objstore.ObjectAttributes{Size: int64(len(buf))}, nil
+}
+
+func (b *stubBucket) Close() error { return nil }
+
+// Iter only implements the non-recursive, single-level semantics this test
+// needs: it returns the direct children of dir.
+func (b *stubBucket) Iter(_ context.Context, dir string, f func(string) error, _ ...objstore.IterOption) error {
+	atomic.AddInt64(&b.iterCalls, 1)
+
+	seen := map[string]struct{}{}
+	for name := range b.objects {
+		rel := name
+		if dir != "" {
+			prefix := dir + "/"
+			if len(name) <= len(prefix) || name[:len(prefix)] != prefix {
+				continue
+			}
+			rel = name[len(prefix):]
+		}
+		child := rel
+		if idx := indexOfSlash(rel); idx >= 0 {
+			child = rel[:idx]
+		}
+		full := child
+		if dir != "" {
+			full = filepath.Join(dir, child)
+		}
+		if _, ok := seen[full]; ok {
+			continue
+		}
+		seen[full] = struct{}{}
+		if err := f(full); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indexOfSlash(s string) int {
+	for i, c := range s {
+		if c == '/' {
+			return i
+		}
+	}
+	return -1
+}
+
+func newTestBlock(t *testing.T, bucket *stubBucket, table string, id ulid.ULID, data string) {
+	t.Helper()
+	name := filepath.Join(table, id.String(), "data.parquet")
+	require.NoError(t, bucket.Upload(context.Background(), name, bytes.NewReader([]byte(data))))
+}
+
+func TestBlockPollerWarmCacheAvoidsBucketCalls(t *testing.T) {
+	bucket := newStubBucket()
+
+	const table = "table1"
+	const numBlocks = 256
+	entropy := ulid.Monotonic(rand.Reader, 0)
+	for i := 0; i < numBlocks; i++ {
+		id := ulid.MustNew(ulid.Now(), entropy)
+		newTestBlock(t, bucket, table, id, "block-data")
+	}
+
+	p := New(nil, bucket, Config{PollInterval: time.Hour}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		blocks, ok := p.Blocks(table, TimeRange{})
+		return ok && len(blocks) == numBlocks
+	}, 5*time.Second, 10*time.Millisecond)
+
+	itersBefore := atomic.LoadInt64(&bucket.iterCalls)
+	attribsBefore := atomic.LoadInt64(&bucket.attributesCalls)
+
+	blocks, ok := p.Blocks(table, TimeRange{})
+	require.True(t, ok)
+	require.Len(t, blocks, numBlocks)
+
+	require.Equal(t, itersBefore, atomic.LoadInt64(&bucket.iterCalls))
+	require.Equal(t, attribsBefore, atomic.LoadInt64(&bucket.attributesCalls))
+
+	p.Shutdown()
+	<-done
+}
+
+func TestBlockPollerTombstonesRemovedBlocks(t *testing.T) {
+	bucket := newStubBucket()
+
+	const table = "table1"
+	id := ulid.MustNew(ulid.Now(), rand.Reader)
+	newTestBlock(t, bucket, table, id, "block-data")
+
+	p := New(nil, bucket, Config{PollInterval: time.Hour}, nil)
+	require.NoError(t, p.pollOnce(context.Background()))
+
+	blocks, ok := p.Blocks(table, TimeRange{})
+	require.True(t, ok)
+	require.Len(t, blocks, 1)
+
+	delete(bucket.objects, filepath.Join(table, id.String(), "data.parquet"))
+	require.NoError(t, p.pollOnce(context.Background()))
+
+	blocks, ok = p.Blocks(table, TimeRange{})
+	require.True(t, ok)
+	require.Empty(t, blocks)
+}
+
+// TestBlockMetaIntersectsUnknownRange guards against blocks discovered via
+// a bare bucket listing (MinTime/MaxTime left at zero, since that path has
+// no access to real row group statistics) being silently excluded from
+// every time-bounded query.
+func TestBlockMetaIntersectsUnknownRange(t *testing.T) {
+	unknown := BlockMeta{}
+	require.True(t, unknown.Intersects(TimeRange{Start: 100, End: 200}))
+	require.True(t, unknown.Intersects(TimeRange{Start: 100}))
+	require.True(t, unknown.Intersects(TimeRange{End: 200}))
+
+	known := BlockMeta{MinTime: 300, MaxTime: 400}
+	require.False(t, known.Intersects(TimeRange{Start: 100, End: 200}))
+	require.True(t, known.Intersects(TimeRange{Start: 350, End: 450}))
+}
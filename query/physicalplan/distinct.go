@@ -3,6 +3,8 @@ package physicalplan
 import (
 	"context"
 	"hash/maphash"
+	"math/bits"
+	"runtime"
 	"sync"
 
 	"github.com/apache/arrow/go/v8/arrow"
@@ -14,6 +16,16 @@ import (
 	"github.com/polarsignals/frostdb/query/logicalplan"
 )
 
+// seenShard is one stripe of the Distinction seen-set. Splitting the set
+// into shards means two goroutines touching different stripes never
+// contend on the same mutex, which is what turns Distinct from a
+// single-lock bottleneck into something that scales with the number of
+// worker goroutines feeding it record batches.
+type seenShard struct {
+	mtx  sync.Mutex
+	seen map[uint64]struct{}
+}
+
 type Distinction struct {
 	pool     memory.Allocator
 	tracer   trace.Tracer
@@ -21,19 +33,37 @@ type Distinction struct {
 	columns  []logicalplan.Expr
 	hashSeed maphash.Seed
 
-	mtx  *sync.RWMutex
-	seen map[uint64]struct{}
+	shards     []*seenShard
+	shardShift uint // number of low bits to drop so shard index = hash >> shardShift
+}
+
+// numShards picks the shard count for a Distinction: the smallest power of
+// two that is >= GOMAXPROCS, so that in the worst case every worker
+// goroutine dispatching batches concurrently can be touching a distinct
+// shard.
+func numShards() int {
+	n := runtime.GOMAXPROCS(0)
+	if n < 1 {
+		n = 1
+	}
+	return 1 << bits.Len(uint(n-1))
 }
 
 func Distinct(pool memory.Allocator, tracer trace.Tracer, columns []logicalplan.Expr) *Distinction {
+	n := numShards()
+	shards := make([]*seenShard, n)
+	for i := range shards {
+		shards[i] = &seenShard{seen: make(map[uint64]struct{})}
+	}
+
 	return &Distinction{
 		pool:     pool,
 		tracer:   tracer,
 		columns:  columns,
 		hashSeed: maphash.MakeSeed(),
 
-		mtx:  &sync.RWMutex{},
-		seen: make(map[uint64]struct{}),
+		shards:     shards,
+		shardShift: 64 - uint(bits.Len(uint(n-1))),
 	}
 }
 
@@ -41,6 +71,13 @@ func (d *Distinction) SetNext(plan PhysicalPlan) {
 	d.next = plan
 }
 
+// shardFor returns the shard responsible for hash, chosen from its top
+// bits so that hashes already well distributed across the full 64 bits
+// (as maphash/scalar.Hash produce) spread evenly across shards too.
+func (d *Distinction) shardFor(hash uint64) *seenShard {
+	return d.shards[hash>>d.shardShift]
+}
+
 func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 	// Generates high volume of spans. Comment out if needed during development.
 	// ctx, span := d.tracer.Start(ctx, "Distinction/Callback")
@@ -64,7 +101,6 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 	for _, arr := range distinctArrays {
 		resBuilders = append(resBuilders, array.NewBuilder(d.pool, arr.DataType()))
 	}
-	rows := int64(0)
 
 	numRows := int(r.NumRows())
 
@@ -73,6 +109,7 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 		colHashes[i] = hashArray(arr)
 	}
 
+	rowHashes := make([]uint64, numRows)
 	for i := 0; i < numRows; i++ {
 		hash := uint64(0)
 		for j := range colHashes {
@@ -88,25 +125,60 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 				),
 			)
 		}
+		rowHashes[i] = hash
+	}
 
-		d.mtx.RLock()
-		if _, ok := d.seen[hash]; ok {
-			d.mtx.RUnlock()
+	// Batched probe pass: group rows by the shard their hash lands in first,
+	// then do a single read-only pass per shard to discard rows we already
+	// know about. This keeps the common case (most rows already seen in a
+	// steady-state, high-cardinality group-by) lock-cheap: one lock
+	// acquisition per shard touched by this batch, not one per row.
+	rowsByShard := make([][]int, len(d.shards))
+	for i := 0; i < numRows; i++ {
+		shardIdx := rowHashes[i] >> d.shardShift
+		rowsByShard[shardIdx] = append(rowsByShard[shardIdx], i)
+	}
+
+	candidatesByShard := make([][]int, len(d.shards))
+	for shardIdx, rowIdxs := range rowsByShard {
+		if len(rowIdxs) == 0 {
 			continue
 		}
-		d.mtx.RUnlock()
 
-		for j, arr := range distinctArrays {
-			err := appendValue(resBuilders[j], arr, i)
-			if err != nil {
-				return err
+		// Every row bucketed here shares the same top bits, so any of their
+		// hashes routes shardFor to the same shard.
+		shard := d.shardFor(rowHashes[rowIdxs[0]])
+		shard.mtx.Lock()
+		for _, rowIdx := range rowIdxs {
+			if _, known := shard.seen[rowHashes[rowIdx]]; !known {
+				candidatesByShard[shardIdx] = append(candidatesByShard[shardIdx], rowIdx)
 			}
 		}
+		shard.mtx.Unlock()
+	}
+
+	// Locked insert pass: one lock acquisition per shard that actually has
+	// candidates, rechecking under the lock since another goroutine may
+	// have inserted the same hash between the probe pass and here.
+	isNew := make([]bool, numRows)
+	rows := int64(0)
+	for shardIdx, candidates := range candidatesByShard {
+		if len(candidates) == 0 {
+			continue
+		}
 
-		rows++
-		d.mtx.Lock()
-		d.seen[hash] = struct{}{}
-		d.mtx.Unlock()
+		shard := d.shardFor(rowHashes[candidates[0]])
+		shard.mtx.Lock()
+		for _, rowIdx := range candidates {
+			hash := rowHashes[rowIdx]
+			if _, ok := shard.seen[hash]; ok {
+				continue
+			}
+			shard.seen[hash] = struct{}{}
+			isNew[rowIdx] = true
+			rows++
+		}
+		shard.mtx.Unlock()
 	}
 
 	if rows == 0 {
@@ -115,6 +187,17 @@ func (d *Distinction) Callback(ctx context.Context, r arrow.Record) error {
 		return nil
 	}
 
+	for i := 0; i < numRows; i++ {
+		if !isNew[i] {
+			continue
+		}
+		for j, arr := range distinctArrays {
+			if err := appendValue(resBuilders[j], arr, i); err != nil {
+				return err
+			}
+		}
+	}
+
 	resArrays := make([]arrow.Array, 0, len(resBuilders))
 	for _, builder := range resBuilders {
 		resArrays = append(resArrays, builder.NewArray())
@@ -0,0 +1,137 @@
+package physicalplan
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/polarsignals/frostdb/query/logicalplan"
+)
+
+// collector is a PhysicalPlan sink that concurrently accumulates every
+// value it sees, for asserting on the union of Distinct's output.
+type collector struct {
+	mtx    sync.Mutex
+	values map[int64]struct{}
+	rows   int // total rows received, duplicates included
+}
+
+func newCollector() *collector {
+	return &collector{values: make(map[int64]struct{})}
+}
+
+func (c *collector) SetNext(PhysicalPlan) {}
+
+func (c *collector) Callback(_ context.Context, r arrow.Record) error {
+	col := r.Column(0).(*array.Int64)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.rows += col.Len()
+	for i := 0; i < col.Len(); i++ {
+		c.values[col.Value(i)] = struct{}{}
+	}
+	return nil
+}
+
+func int64Record(pool memory.Allocator, values []int64) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "value", Type: arrow.PrimitiveTypes.Int64}}, nil)
+
+	b := array.NewInt64Builder(pool)
+	defer b.Release()
+	b.AppendValues(values, nil)
+	arr := b.NewArray()
+	defer arr.Release()
+
+	return array.NewRecord(schema, []arrow.Array{arr}, int64(len(values)))
+}
+
+// TestDistinctionConcurrentCorrectness dispatches overlapping batches from
+// many goroutines at once (run with -race) and asserts that the union of
+// everything Distinct forwards downstream equals the sequential distinct
+// set, i.e. sharding the seen-set didn't let duplicates slip through or
+// drop legitimately new values.
+func TestDistinctionConcurrentCorrectness(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	tracer := trace.NewNoopTracerProvider().Tracer("test")
+
+	const (
+		numGoroutines = 8
+		numBatches    = 50
+		batchSize     = 200
+		cardinality   = 500 // values are in [0, cardinality), so heavily overlapping
+	)
+
+	out := newCollector()
+	d := Distinct(pool, tracer, []logicalplan.Expr{logicalplan.Col("value")})
+	d.SetNext(out)
+
+	var wg sync.WaitGroup
+	for g := 0; g < numGoroutines; g++ {
+		wg.Add(1)
+		go func(seed int) {
+			defer wg.Done()
+			for b := 0; b < numBatches; b++ {
+				values := make([]int64, batchSize)
+				for i := range values {
+					values[i] = int64((seed*31 + b*7 + i) % cardinality)
+				}
+				rec := int64Record(pool, values)
+				require.NoError(t, d.Callback(context.Background(), rec))
+				rec.Release()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	require.Len(t, out.values, cardinality)
+	require.Equal(t, cardinality, out.rows)
+}
+
+// BenchmarkDistinctionConcurrent measures Distinct.Callback throughput when
+// fed from multiple producer goroutines simultaneously, the scenario the
+// sharded seen-set targets (a single shared mutex serializes all of this
+// work; sharding lets producers touching different stripes proceed without
+// contending).
+func BenchmarkDistinctionConcurrent(b *testing.B) {
+	for _, producers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("producers=%d", producers), func(b *testing.B) {
+			pool := memory.NewGoAllocator()
+			tracer := trace.NewNoopTracerProvider().Tracer("bench")
+
+			out := newCollector()
+			d := Distinct(pool, tracer, []logicalplan.Expr{logicalplan.Col("value")})
+			d.SetNext(out)
+
+			const batchSize = 1024
+			const cardinality = 1_000_000
+
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			rowsPerProducer := b.N / producers
+			for p := 0; p < producers; p++ {
+				wg.Add(1)
+				go func(seed int) {
+					defer wg.Done()
+					values := make([]int64, batchSize)
+					for done := 0; done < rowsPerProducer; done += batchSize {
+						for i := range values {
+							values[i] = int64((seed*2_654_435_761 + done + i) % cardinality)
+						}
+						rec := int64Record(pool, values)
+						_ = d.Callback(context.Background(), rec)
+						rec.Release()
+					}
+				}(p)
+			}
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package physicalplan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/ipc"
+)
+
+// IPCWriter is a PhysicalPlan sink that streams every record it receives to
+// w using the standard Arrow IPC stream framing (the format BigQuery
+// Storage, DuckDB and pyarrow all read natively), in addition to forwarding
+// the record down the chain like any other physical plan step.
+//
+// The underlying ipc.Writer is created lazily from the schema of the first
+// record seen, and reused for every subsequent batch so that the schema is
+// written once per stream. It is created with default options, so dictionary
+// deltas are not enabled. Close must be called once the plan has finished
+// producing records to write the end-of-stream marker.
+type IPCWriter struct {
+	next PhysicalPlan
+
+	w io.Writer
+
+	mtx    sync.Mutex
+	writer *ipc.Writer
+}
+
+// NewIPCWriter returns a PhysicalPlan that streams records to w in Arrow IPC
+// stream format as they flow through the plan.
+func NewIPCWriter(w io.Writer) *IPCWriter {
+	return &IPCWriter{w: w}
+}
+
+func (i *IPCWriter) SetNext(next PhysicalPlan) {
+	i.next = next
+}
+
+func (i *IPCWriter) Callback(ctx context.Context, r arrow.Record) error {
+	if err := i.write(r); err != nil {
+		return err
+	}
+
+	if i.next == nil {
+		return nil
+	}
+	return i.next.Callback(ctx, r)
+}
+
+func (i *IPCWriter) write(r arrow.Record) error {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	if i.writer == nil {
+		i.writer = ipc.NewWriter(i.w, ipc.WithSchema(r.Schema()))
+	}
+
+	if err := i.writer.Write(r); err != nil {
+		return fmt.Errorf("write ipc record: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the underlying IPC writer. It is a no-op if no
+// record was ever written (e.g. the query returned zero rows).
+func (i *IPCWriter) Close() error {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	if i.writer == nil {
+		return nil
+	}
+	return i.writer.Close()
+}
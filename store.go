@@ -1,11 +1,15 @@
 package frostdb
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"path/filepath"
 
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/oklog/ulid"
@@ -14,6 +18,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/poller"
 )
 
 // Persist uploads the block to the underlying bucket.
@@ -30,18 +35,137 @@ func (t *TableBlock) Persist() error {
 	}()
 	defer r.Close()
 
+	// Tee the serialized bytes into statsBuf as they're uploaded, so the
+	// table index manifest below can be built from the exact bytes that
+	// were written, without paying for a second Serialize call.
+	var statsBuf bytes.Buffer
+	tr := io.TeeReader(r, &statsBuf)
+
 	fileName := filepath.Join(t.table.name, t.ulid.String(), "data.parquet")
-	if err := t.table.db.bucket.Upload(context.Background(), fileName, r); err != nil {
+	ctx := context.Background()
+	if err := t.table.db.bucket.Upload(ctx, fileName, tr); err != nil {
 		return fmt.Errorf("failed to upload block %v", err)
 	}
 
 	if err != nil {
 		return fmt.Errorf("failed to serialize block: %v", err)
 	}
+
+	// Keep the table's index manifest in sync so a cold BlockPoller can
+	// find this block with a single GET instead of a full bucket listing.
+	attribs, err := t.table.db.bucket.Attributes(ctx, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to stat persisted block: %v", err)
+	}
+
+	minTime, maxTime, _ := blockTimeRange(t.table.schema, statsBuf.Bytes())
+	if err := poller.AppendBlockToIndex(ctx, t.table.db.bucket, t.table.name, poller.BlockMeta{
+		ULID:    t.ulid,
+		Size:    attribs.Size,
+		MinTime: minTime,
+		MaxTime: maxTime,
+		Table:   t.table.name,
+	}); err != nil {
+		return fmt.Errorf("failed to update table index: %v", err)
+	}
+
 	return nil
 }
 
-func (t *Table) IterateBucketBlocks(ctx context.Context, logger log.Logger, filter TrueNegativeFilter, iterator func(rg dynparquet.DynamicRowGroup) bool, lastBlockTimestamp uint64) error {
+// blockTimeRange computes the [min, max] nanosecond range of a serialized
+// block's primary sorting column (conventionally the time column for
+// time-series schemas), the same statistics Serialize already wrote into
+// the parquet file. ok is false if the schema has no sorting column, the
+// column isn't an INT64 column, or the block has no rows, in which case
+// callers should leave the range unset rather than guess.
+func blockTimeRange(schema *dynparquet.Schema, serialized []byte) (minTime, maxTime int64, ok bool) {
+	sortingColumns := schema.SortingColumns()
+	if len(sortingColumns) == 0 {
+		return 0, 0, false
+	}
+	timeColumn := sortingColumns[0].ColumnName()
+
+	file, err := parquet.OpenFile(bytes.NewReader(serialized), int64(len(serialized)))
+	if err != nil {
+		return 0, 0, false
+	}
+	buf, err := dynparquet.NewSerializedBuffer(file)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	pool := memory.NewGoAllocator()
+	seen := false
+	for i := 0; i < buf.NumRowGroups(); i++ {
+		record, err := dynparquet.RowGroupToArrowRecord(pool, buf.DynamicRowGroup(i))
+		if err != nil {
+			return 0, 0, false
+		}
+
+		idx := -1
+		for j, field := range record.Schema().Fields() {
+			if field.Name == timeColumn {
+				idx = j
+				break
+			}
+		}
+		col, isInt64 := indexedInt64Column(record, idx)
+		if isInt64 {
+			for k := 0; k < col.Len(); k++ {
+				if col.IsNull(k) {
+					continue
+				}
+				v := col.Value(k)
+				if !seen {
+					minTime, maxTime, seen = v, v, true
+					continue
+				}
+				if v < minTime {
+					minTime = v
+				}
+				if v > maxTime {
+					maxTime = v
+				}
+			}
+		}
+		record.Release()
+	}
+
+	return minTime, maxTime, seen
+}
+
+func indexedInt64Column(record arrow.Record, idx int) (*array.Int64, bool) {
+	if idx < 0 {
+		return nil, false
+	}
+	col, ok := record.Column(idx).(*array.Int64)
+	return col, ok
+}
+
+// rowGroupSource is the subset of *dynparquet.SerializedBuffer that
+// IterateBucketBlocks needs. It exists so tests can substitute openBlockFile
+// with a fake and exercise the cache-hit path without real parquet bytes.
+type rowGroupSource interface {
+	NumRowGroups() int
+	DynamicRowGroup(i int) dynparquet.DynamicRowGroup
+}
+
+// openBlockFile opens a block's data.parquet bytes as a rowGroupSource.
+// Overridden in tests.
+var openBlockFile = func(r io.ReaderAt, size int64) (rowGroupSource, error) {
+	file, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return dynparquet.NewSerializedBuffer(file)
+}
+
+// IterateBucketBlocks iterates over all blocks of the table found in the
+// bucket and, for each row group that may contain useful data according to
+// filter, calls iterator. timeRange, when non-zero, is used to skip blocks
+// whose cached [minTime, maxTime] range cannot intersect the query, without
+// opening the block at all.
+func (t *Table) IterateBucketBlocks(ctx context.Context, logger log.Logger, filter TrueNegativeFilter, iterator func(rg dynparquet.DynamicRowGroup) bool, lastBlockTimestamp uint64, timeRange poller.TimeRange) error {
 	ctx, span := t.tracer.Start(ctx, "Table/IterateBucketBlocks")
 	span.SetAttributes(attribute.Int64("lastBlockTimestamp", int64(lastBlockTimestamp)))
 	defer span.End()
@@ -51,28 +175,8 @@ func (t *Table) IterateBucketBlocks(ctx context.Context, logger log.Logger, filt
 	}
 
 	n := 0
-	err := t.db.bucket.Iter(ctx, t.name, func(blockDir string) error {
-		ctx, span := t.tracer.Start(ctx, "Table/IterateBucketBlocks/Iter")
-		defer span.End()
-
-		blockUlid, err := ulid.Parse(filepath.Base(blockDir))
-		if err != nil {
-			return err
-		}
-
-		span.SetAttributes(attribute.String("ulid", blockUlid.String()))
-
-		if lastBlockTimestamp != 0 && blockUlid.Time() >= lastBlockTimestamp {
-			return nil
-		}
-
+	openAndIterate := func(ctx context.Context, blockUlid ulid.ULID, blockDir string, size int64) error {
 		blockName := filepath.Join(blockDir, "data.parquet")
-		attribs, err := t.db.bucket.Attributes(ctx, blockName)
-		if err != nil {
-			return err
-		}
-
-		span.SetAttributes(attribute.Int64("size", attribs.Size))
 
 		b := &BucketReaderAt{
 			name:   blockName,
@@ -80,36 +184,74 @@ func (t *Table) IterateBucketBlocks(ctx context.Context, logger log.Logger, filt
 			Bucket: t.db.bucket,
 		}
 
-		file, err := parquet.OpenFile(b, attribs.Size)
-		if err != nil {
-			return err
-		}
-
-		// Get a reader from the file bytes
-		buf, err := dynparquet.NewSerializedBuffer(file)
+		buf, err := openBlockFile(b, size)
 		if err != nil {
 			return err
 		}
 
 		n++
 		for i := 0; i < buf.NumRowGroups(); i++ {
-			span.AddEvent("rowgroup")
-
 			rg := buf.DynamicRowGroup(i)
-			var mayContainUsefulData bool
-			mayContainUsefulData, err = filter.Eval(rg)
+			mayContainUsefulData, err := filter.Eval(rg)
 			if err != nil {
 				return err
 			}
 			if mayContainUsefulData {
 				if continu := iterator(rg); !continu {
-					return err
+					return nil
 				}
 			}
 		}
 		return nil
+	}
+
+	if t.db.blockPoller != nil {
+		if blocks, ok := t.db.blockPoller.Blocks(t.name, timeRange); ok {
+			for _, blk := range blocks {
+				if lastBlockTimestamp != 0 && blk.ULID.Time() >= lastBlockTimestamp {
+					continue
+				}
+				ctx, span := t.tracer.Start(ctx, "Table/IterateBucketBlocks/CachedBlock")
+				span.SetAttributes(attribute.String("ulid", blk.ULID.String()), attribute.Int64("size", blk.Size))
+				blockDir := filepath.Join(t.name, blk.ULID.String())
+				err := openAndIterate(ctx, blk.ULID, blockDir, blk.Size)
+				span.End()
+				if err != nil {
+					return err
+				}
+			}
+			level.Debug(logger).Log("msg", "read blocks", "n", n, "source", "cache")
+			return nil
+		}
+	}
+
+	err := t.db.bucket.Iter(ctx, t.name, func(blockDir string) error {
+		ctx, span := t.tracer.Start(ctx, "Table/IterateBucketBlocks/Iter")
+		defer span.End()
+
+		blockUlid, err := ulid.Parse(filepath.Base(blockDir))
+		if err != nil {
+			// Not a block directory (e.g. the table's index manifest).
+			return nil
+		}
+
+		span.SetAttributes(attribute.String("ulid", blockUlid.String()))
+
+		if lastBlockTimestamp != 0 && blockUlid.Time() >= lastBlockTimestamp {
+			return nil
+		}
+
+		blockName := filepath.Join(blockDir, "data.parquet")
+		attribs, err := t.db.bucket.Attributes(ctx, blockName)
+		if err != nil {
+			return err
+		}
+
+		span.SetAttributes(attribute.Int64("size", attribs.Size))
+
+		return openAndIterate(ctx, blockUlid, blockDir, attribs.Size)
 	})
-	level.Debug(logger).Log("msg", "read blocks", "n", n)
+	level.Debug(logger).Log("msg", "read blocks", "n", n, "source", "bucket")
 	return err
 }
 
@@ -0,0 +1,100 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+	"github.com/polarsignals/frostdb/poller"
+)
+
+// countingBucket wraps an objstore.Bucket and counts Iter/Attributes calls,
+// so tests can assert IterateBucketBlocks avoided them on a warm cache.
+type countingBucket struct {
+	objstore.Bucket
+	iterCalls       int64
+	attributesCalls int64
+}
+
+func (b *countingBucket) Iter(ctx context.Context, dir string, f func(string) error, opts ...objstore.IterOption) error {
+	atomic.AddInt64(&b.iterCalls, 1)
+	return b.Bucket.Iter(ctx, dir, f, opts...)
+}
+
+func (b *countingBucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	atomic.AddInt64(&b.attributesCalls, 1)
+	return b.Bucket.Attributes(ctx, name)
+}
+
+// emptyRowGroupSource is a rowGroupSource with no row groups, so
+// IterateBucketBlocks's iterator callback never needs to run.
+type emptyRowGroupSource struct{}
+
+func (emptyRowGroupSource) NumRowGroups() int                              { return 0 }
+func (emptyRowGroupSource) DynamicRowGroup(int) dynparquet.DynamicRowGroup { return nil }
+
+// allowAllFilter is a TrueNegativeFilter that never excludes a row group.
+type allowAllFilter struct{}
+
+func (allowAllFilter) Eval(dynparquet.DynamicRowGroup) (bool, error) { return true, nil }
+
+// TestIterateBucketBlocksWarmCacheAvoidsBucketCalls asserts that once the
+// block poller's cache is warm, IterateBucketBlocks serves the query
+// entirely from the cache and makes zero bucket Iter/Attributes calls.
+func TestIterateBucketBlocksWarmCacheAvoidsBucketCalls(t *testing.T) {
+	prevOpenBlockFile := openBlockFile
+	openBlockFile = func(r io.ReaderAt, size int64) (rowGroupSource, error) {
+		return emptyRowGroupSource{}, nil
+	}
+	defer func() { openBlockFile = prevOpenBlockFile }()
+
+	const tableName = "table1"
+	bucket := &countingBucket{Bucket: objstore.NewInMemBucket()}
+
+	id := ulid.MustNew(ulid.Now(), rand.Reader)
+	blockName := filepath.Join(tableName, id.String(), "data.parquet")
+	require.NoError(t, bucket.Upload(context.Background(), blockName, bytes.NewReader([]byte("block-data"))))
+
+	blockPoller := poller.New(nil, bucket, poller.Config{PollInterval: time.Hour}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go blockPoller.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		blocks, ok := blockPoller.Blocks(tableName, poller.TimeRange{})
+		return ok && len(blocks) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	blockPoller.Shutdown()
+
+	atomic.StoreInt64(&bucket.iterCalls, 0)
+	atomic.StoreInt64(&bucket.attributesCalls, 0)
+
+	table := &Table{
+		name: tableName,
+		db: &DB{
+			bucket:      bucket,
+			blockPoller: blockPoller,
+		},
+		tracer: trace.NewNoopTracerProvider().Tracer(""),
+	}
+
+	err := table.IterateBucketBlocks(context.Background(), log.NewNopLogger(), allowAllFilter{}, func(rg dynparquet.DynamicRowGroup) bool {
+		return true
+	}, 0, poller.TimeRange{})
+	require.NoError(t, err)
+
+	require.Equal(t, int64(0), atomic.LoadInt64(&bucket.iterCalls))
+	require.Equal(t, int64(0), atomic.LoadInt64(&bucket.attributesCalls))
+}
@@ -0,0 +1,44 @@
+package frostdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/polarsignals/frostdb/dynparquet"
+)
+
+// SchemaFromStruct returns the dynparquet.Schema that values of type t
+// encode to, building and caching the struct's insertion plan the first
+// time t is seen. It is a thin convenience wrapper around
+// dynparquet.SchemaFromStruct for callers that only have a Table handy.
+func (t *Table) SchemaFromStruct(typ reflect.Type) (*dynparquet.Schema, error) {
+	return dynparquet.SchemaFromStruct(typ)
+}
+
+// InsertStructs encodes rows via the cached reflection plan for T and
+// inserts them into the table, the way InsertRecord or InsertBuffer would
+// for an already-parquet-encoded source. The first call for a given struct
+// type pays the cost of reflecting over its fields; every subsequent call,
+// for this or any other table, reuses that plan.
+func InsertStructs[T any](ctx context.Context, t *Table, rows []T) (tx uint64, err error) {
+	typ := reflect.TypeOf(*new(T))
+
+	plan, err := dynparquet.PlanForStruct(typ)
+	if err != nil {
+		return 0, fmt.Errorf("frostdb: building plan for %s: %w", typ, err)
+	}
+
+	buf, err := plan.Rows(reflect.ValueOf(rows))
+	if err != nil {
+		return 0, fmt.Errorf("frostdb: encoding rows: %w", err)
+	}
+
+	var serialized bytes.Buffer
+	if err := buf.WriteTo(&serialized); err != nil {
+		return 0, fmt.Errorf("frostdb: serializing rows: %w", err)
+	}
+
+	return t.Insert(ctx, serialized.Bytes())
+}